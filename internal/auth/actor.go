@@ -0,0 +1,25 @@
+package auth
+
+import "context"
+
+// Actor identifies who is responsible for an action, for attribution in audit trails and
+// similar records.
+type Actor struct {
+	ID   string
+	Name string
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying actor, for a request handler to attach once a caller
+// has been authenticated.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the Actor attached to ctx, if any. It returns false for contexts
+// with no attached actor, such as system-initiated work like a projection replay.
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
@@ -0,0 +1,167 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	student "geevly/events/gen/proto/go"
+
+	"github.com/Howard3/gosignal"
+	_ "modernc.org/sqlite"
+)
+
+// newAuditLogTestDB opens an in-memory sqlite database with the student_audit_log table created,
+// the same schema insertAuditEntry writes through.
+func newAuditLogTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("opening test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(auditLogSchema); err != nil {
+		t.Fatalf("creating audit log schema: %v", err)
+	}
+
+	return db
+}
+
+// TestAuditLogHandler_Handle_SetLookupCodeAndUnenroll verifies that Handle records
+// SetLookupCode and UnenrollStudent events instead of failing with ErrEventNotFound. Both fire
+// during ordinary student lifecycle operations (code rotation, unenrollment), and until
+// newEventPayload knew about them, every occurrence would make Handle return an error, which
+// sticks the audit-log outbox subscriber's cursor permanently (see outbox.go's deliverTo).
+func TestAuditLogHandler_Handle_SetLookupCodeAndUnenroll(t *testing.T) {
+	ctx := context.Background()
+
+	sd := &StudentData{}
+	sd.ID = "42"
+
+	createEvt, err := sd.CreateStudent(&student.AddStudentEvent{
+		FirstName: "Ada", LastName: "Lovelace", DateOfBirth: "1815-12-10", SchoolId: "1",
+	})
+	if err != nil {
+		t.Fatalf("creating student: %v", err)
+	}
+
+	lookupEvt, err := sd.SetLookupCode(&student.SetLookupCodeEvent{Code: "abc123"}, sd.GetVersion()+1)
+	if err != nil {
+		t.Fatalf("setting lookup code: %v", err)
+	}
+
+	unenrollEvt, err := sd.UnenrollStudent(&student.UnenrollStudentEvent{}, sd.GetVersion()+1)
+	if err != nil {
+		t.Fatalf("unenrolling student: %v", err)
+	}
+
+	db := newAuditLogTestDB(t)
+	repo := &Repository{db: db, store: newInMemoryEventStore()}
+	handler := NewAuditLogHandler(repo)
+
+	for _, evt := range []*gosignal.Event{createEvt, lookupEvt, unenrollEvt} {
+		if err := handler.Handle(ctx, evt); err != nil {
+			t.Fatalf("handling event %q: %v", evt.Type, err)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT event_type FROM student_audit_log ORDER BY id`)
+	if err != nil {
+		t.Fatalf("querying audit log: %v", err)
+	}
+	defer rows.Close()
+
+	var gotTypes []string
+
+	for rows.Next() {
+		var eventType string
+		if err := rows.Scan(&eventType); err != nil {
+			t.Fatalf("scanning row: %v", err)
+		}
+
+		gotTypes = append(gotTypes, eventType)
+	}
+
+	want := []string{EVENT_ADD_STUDENT, EVENT_SET_LOOKUP_CODE, EVENT_UNENROLL_STUDENT}
+
+	if len(gotTypes) != len(want) {
+		t.Fatalf("recorded %d audit entries, want %d: %v", len(gotTypes), len(want), gotTypes)
+	}
+
+	for i, eventType := range want {
+		if gotTypes[i] != eventType {
+			t.Fatalf("entry %d event_type = %q, want %q", i, gotTypes[i], eventType)
+		}
+	}
+}
+
+// TestAuditLogHandler_Handle_RetriesAfterInsertFailure verifies that when insertAuditEntry fails
+// for an event - simulated here by dropping the table out from under it - the outbox's redelivery
+// of that same event still produces the correct diff, rather than the empty no-op diff that
+// regression would produce if Handle had already cached the post-event state on the failed
+// attempt (see diffState's doc comment).
+func TestAuditLogHandler_Handle_RetriesAfterInsertFailure(t *testing.T) {
+	ctx := context.Background()
+
+	sd := &StudentData{}
+	sd.ID = "42"
+
+	createEvt, err := sd.CreateStudent(&student.AddStudentEvent{
+		FirstName: "Ada", LastName: "Lovelace", DateOfBirth: "1815-12-10", SchoolId: "1",
+	})
+	if err != nil {
+		t.Fatalf("creating student: %v", err)
+	}
+
+	updateEvt, err := sd.UpdateStudent(&student.UpdateStudentEvent{
+		FirstName: "Ada", LastName: "King", DateOfBirth: "1815-12-10", SchoolId: "1",
+	}, sd.GetVersion()+1)
+	if err != nil {
+		t.Fatalf("updating student: %v", err)
+	}
+
+	db := newAuditLogTestDB(t)
+	repo := &Repository{db: db, store: newInMemoryEventStore()}
+	handler := NewAuditLogHandler(repo)
+
+	if err := handler.Handle(ctx, createEvt); err != nil {
+		t.Fatalf("handling create event: %v", err)
+	}
+
+	// Simulate a transient failure of insertAuditEntry for updateEvt: the table it writes to is
+	// briefly unavailable, so the first Handle call must fail without caching updateEvt's result.
+	if _, err := db.Exec(`DROP TABLE student_audit_log`); err != nil {
+		t.Fatalf("dropping audit log table: %v", err)
+	}
+
+	if err := handler.Handle(ctx, updateEvt); err == nil {
+		t.Fatal("Handle with table unavailable: want error, got nil")
+	}
+
+	if _, err := db.Exec(auditLogSchema); err != nil {
+		t.Fatalf("recreating audit log table: %v", err)
+	}
+
+	// The outbox redelivers the same, unchanged event on failure (see outbox.go's deliverRow).
+	if err := handler.Handle(ctx, updateEvt); err != nil {
+		t.Fatalf("handling redelivered update event: %v", err)
+	}
+
+	var diff string
+
+	row := db.QueryRowContext(ctx, `SELECT diff FROM student_audit_log WHERE event_type = ?`, EVENT_UPDATE_STUDENT)
+	if err := row.Scan(&diff); err != nil {
+		t.Fatalf("scanning update event diff: %v", err)
+	}
+
+	if diff == "{}" {
+		t.Fatalf("update event diff = %q, want a non-empty diff reflecting the last-name change", diff)
+	}
+
+	if !strings.Contains(diff, "King") {
+		t.Fatalf("update event diff = %q, want it to mention the new last name", diff)
+	}
+}
@@ -2,77 +2,55 @@ package student
 
 import (
 	"context"
-	"log/slog"
-	"strconv"
+
+	"geevly/internal/webhook"
 
 	"github.com/Howard3/gosignal"
 )
 
-type eventHandlers struct {
-	repo Repository
+// EventHandler is a named, pluggable consumer of student events. Handlers compose by registering
+// with eventHandlers.Register rather than being hard-coded into a dispatch switch, so new read
+// models (audit logging, webhooks, search indexing, ...) can be added without touching existing
+// ones.
+type EventHandler interface {
+	Name() string
+	Handle(ctx context.Context, evt *gosignal.Event) error
 }
 
-func NewEventHandlers(repo Repository) *eventHandlers {
-	return &eventHandlers{
-		repo: repo,
-	}
+type eventHandlers struct {
+	repo       Repository
+	dispatcher *outboxDispatcher
 }
 
-// HandleNewStudentEvent is a method that handles the NewStudentEvent
-// it loads the student aggregate from the repository and projects it to the database
-func (eh *eventHandlers) HandleNewStudentEvent(ctx context.Context, aggregateID uint64) {
-	student, err := eh.repo.loadStudent(ctx, aggregateID)
-	if err != nil {
-		slog.Error("failed to load student", "error", err)
-		return
+// NewEventHandlers wires up the student package's default handlers - read-model projections,
+// audit logging, and outbound webhooks - and starts the background outbox dispatcher that drives
+// them. webhooks may be nil, in which case no webhook handler is registered.
+func NewEventHandlers(repo Repository, webhooks *webhook.Repository) *eventHandlers {
+	eh := &eventHandlers{
+		repo:       repo,
+		dispatcher: newOutboxDispatcher(repo.db),
 	}
 
-	if err := eh.repo.upsertStudent(student); err != nil {
-		slog.Error("failed to upsert student", "error", err)
-		return
-	}
-}
+	eh.Register("student-projection", &studentProjectionHandler{repo: &eh.repo})
+	eh.Register("audit-log", NewAuditLogHandler(&eh.repo))
 
-// HandleUpdateStudentEvent is a method that handles the UpdateStudentEvent
-// functionally the same as HandleNewStudentEvent, thus it just aliases it
-func (eh *eventHandlers) HandleUpdateStudentEvent(ctx context.Context, aggID uint64) {
-	eh.HandleNewStudentEvent(ctx, aggID)
-}
-
-// HandleGenerateCodeEvent is a method that handles the GenerateCodeEvent
-func (eh *eventHandlers) HandleGenerateCodeEvent(ctx context.Context, aggID uint64) {
-	student, err := eh.repo.loadStudent(ctx, aggID)
-	if err != nil {
-		slog.Error("failed to load student", "error", err)
-		return
+	if webhooks != nil {
+		eh.Register("webhooks", NewWebhookHandler(webhooks))
 	}
 
-	code := student.data.CodeUniqueId
-	if len(code) == 0 {
-		slog.Error("code is empty")
-		return
-	}
+	go eh.dispatcher.run(context.Background())
 
-	if err := eh.repo.insertStudentCode(ctx, aggID, code); err != nil {
-		slog.Error("failed to insert student code", "error", err)
-		return
-	}
+	return eh
 }
 
-// routeEvent is a method that routes an event to the appropriate handler
-func (eh *eventHandlers) routeEvent(ctx context.Context, evt *gosignal.Event) {
-	id, err := strconv.ParseUint(evt.AggregateID, 10, 64)
-	if err != nil {
-		slog.Error("failed to parse aggregate ID", "error", err)
-		return
-	}
+// Register adds h to the chain of handlers invoked for every student event, delivered
+// at-least-once via the outbox under the given subscriber name.
+func (eh *eventHandlers) Register(name string, h EventHandler) {
+	eh.dispatcher.Subscribe(name, h.Handle)
+}
 
-	switch evt.Type {
-	case EVENT_ADD_STUDENT:
-		eh.HandleNewStudentEvent(ctx, id)
-	case EVENT_UPDATE_STUDENT, EVENT_ENROLL_STUDENT, EVENT_UNENROLL_STUDENT, EVENT_SET_STUDENT_STATUS:
-		eh.HandleUpdateStudentEvent(ctx, id)
-	case EVENT_SET_LOOKUP_CODE:
-		eh.HandleGenerateCodeEvent(ctx, id)
-	}
+// ReplayFrom rebuilds subscriberName's projection by redelivering every outbox row after
+// fromVersion, for backfilling a newly registered handler.
+func (eh *eventHandlers) ReplayFrom(ctx context.Context, subscriberName string, fromVersion int64) error {
+	return eh.dispatcher.ReplayFrom(ctx, subscriberName, fromVersion)
 }
@@ -0,0 +1,106 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/Howard3/gosignal"
+)
+
+// sqliteEventStore is an EventStore backed by a sqlite table, stored alongside the outbox and
+// snapshot tables. The unique constraint on (aggregate_id, version) is what makes AppendEventTx's
+// optimistic-concurrency check reliable under concurrent writers: two callers racing to append
+// the same (aggregate_id, expectedVersion) can't both succeed, so the loser gets ErrVersionMismatch
+// instead of silently clobbering the winner's event.
+type sqliteEventStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteEventStore creates an EventStore backed by the given sqlite database. The caller is
+// responsible for running the schema migration that creates the student_events table.
+func NewSQLiteEventStore(db *sql.DB) EventStore {
+	return &sqliteEventStore{db: db}
+}
+
+const sqliteEventStoreSchema = `
+CREATE TABLE IF NOT EXISTS student_events (
+	aggregate_id TEXT NOT NULL,
+	version      INTEGER NOT NULL,
+	event_type   TEXT NOT NULL,
+	data         BLOB NOT NULL,
+	timestamp    DATETIME NOT NULL,
+	PRIMARY KEY (aggregate_id, version)
+);`
+
+// GetEvents returns aggID's events with Version greater than afterVersion, in version order.
+func (s *sqliteEventStore) GetEvents(ctx context.Context, aggID string, afterVersion uint) ([]gosignal.Event, error) {
+	const q = `
+SELECT event_type, version, data, timestamp FROM student_events
+WHERE aggregate_id = ? AND version > ?
+ORDER BY version ASC`
+
+	rows, err := s.db.QueryContext(ctx, q, aggID, afterVersion)
+	if err != nil {
+		return nil, fmt.Errorf("loading events for %q: %w", aggID, err)
+	}
+	defer rows.Close()
+
+	var events []gosignal.Event
+
+	for rows.Next() {
+		evt := gosignal.Event{AggregateID: aggID}
+
+		if err := rows.Scan(&evt.Type, &evt.Version, &evt.Data, &evt.Timestamp); err != nil {
+			return nil, fmt.Errorf("scanning event for %q: %w", aggID, err)
+		}
+
+		events = append(events, evt)
+	}
+
+	return events, rows.Err()
+}
+
+// AppendEventTx persists evt for aggID within tx, keyed on (aggID, expectedVersion). A racing
+// writer that already committed expectedVersion trips the table's unique constraint; AppendEventTx
+// translates that into ErrVersionMismatch so Repository.WithRetry can reload and retry, rather
+// than letting the raw driver error leak out as an opaque SQL failure.
+func (s *sqliteEventStore) AppendEventTx(ctx context.Context, tx *sql.Tx, aggID string, evt gosignal.Event, expectedVersion uint) error {
+	const q = `INSERT INTO student_events (aggregate_id, version, event_type, data, timestamp) VALUES (?, ?, ?, ?, ?)`
+
+	_, err := tx.ExecContext(ctx, q, aggID, expectedVersion, evt.Type, evt.Data, evt.Timestamp)
+	if err == nil {
+		return nil
+	}
+
+	if !isUniqueConstraintErr(err) {
+		return fmt.Errorf("appending event for %q: %w", aggID, err)
+	}
+
+	actual, lookupErr := s.currentVersion(ctx, tx, aggID)
+	if lookupErr != nil {
+		return fmt.Errorf("looking up current version for %q after conflict: %w", aggID, lookupErr)
+	}
+
+	return ErrVersionMismatch{Expected: expectedVersion, Actual: actual}
+}
+
+// currentVersion returns the highest version committed for aggID, or 0 if it has no events yet.
+func (s *sqliteEventStore) currentVersion(ctx context.Context, tx *sql.Tx, aggID string) (uint, error) {
+	var version uint
+
+	row := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM student_events WHERE aggregate_id = ?`, aggID)
+	if err := row.Scan(&version); err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// isUniqueConstraintErr reports whether err is a sqlite unique-constraint violation - the
+// mechanism by which AppendEventTx detects a racing writer - independent of which sqlite driver
+// is in use, since both the common pure-Go and cgo drivers surface this in the error string.
+func isUniqueConstraintErr(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
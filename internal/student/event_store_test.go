@@ -0,0 +1,118 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Howard3/gosignal"
+)
+
+// newEventStoreTestDB opens an in-memory sqlite database with the student_events table created.
+func newEventStoreTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("opening test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(sqliteEventStoreSchema); err != nil {
+		t.Fatalf("creating event store schema: %v", err)
+	}
+
+	return db
+}
+
+// TestSQLiteEventStore_AppendAndGetEvents verifies that an appended event round-trips through
+// GetEvents with its type, version, data and timestamp intact.
+func TestSQLiteEventStore_AppendAndGetEvents(t *testing.T) {
+	ctx := context.Background()
+	db := newEventStoreTestDB(t)
+	store := NewSQLiteEventStore(db)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("beginning transaction: %v", err)
+	}
+
+	evt := gosignal.Event{
+		AggregateID: "42",
+		Type:        EVENT_ADD_STUDENT,
+		Version:     0,
+		Data:        []byte("payload"),
+		Timestamp:   time.Unix(1700000000, 0).UTC(),
+	}
+
+	if err := store.AppendEventTx(ctx, tx, "42", evt, 0); err != nil {
+		t.Fatalf("appending event: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("committing transaction: %v", err)
+	}
+
+	events, err := store.GetEvents(ctx, "42", 0)
+	if err != nil {
+		t.Fatalf("loading events: %v", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+
+	got := events[0]
+	if got.Type != evt.Type || got.Version != evt.Version || string(got.Data) != string(evt.Data) || !got.Timestamp.Equal(evt.Timestamp) {
+		t.Fatalf("round-tripped event = %+v, want %+v", got, evt)
+	}
+}
+
+// TestSQLiteEventStore_AppendEventTx_VersionConflict verifies that appending a second event at a
+// version that's already committed returns ErrVersionMismatch rather than silently overwriting it,
+// the optimistic-concurrency guarantee Repository.WithRetry depends on.
+func TestSQLiteEventStore_AppendEventTx_VersionConflict(t *testing.T) {
+	ctx := context.Background()
+	db := newEventStoreTestDB(t)
+	store := NewSQLiteEventStore(db)
+
+	commit := func(evt gosignal.Event, expectedVersion uint) error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("beginning transaction: %v", err)
+		}
+		defer tx.Rollback()
+
+		if err := store.AppendEventTx(ctx, tx, "42", evt, expectedVersion); err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}
+
+	first := gosignal.Event{AggregateID: "42", Type: EVENT_ADD_STUDENT, Version: 0, Data: []byte("first"), Timestamp: time.Unix(1700000000, 0).UTC()}
+	if err := commit(first, 0); err != nil {
+		t.Fatalf("committing first event: %v", err)
+	}
+
+	second := gosignal.Event{AggregateID: "42", Type: EVENT_UPDATE_STUDENT, Version: 0, Data: []byte("second"), Timestamp: time.Unix(1700000001, 0).UTC()}
+
+	err := commit(second, 0)
+	if err == nil {
+		t.Fatal("committing conflicting event: want ErrVersionMismatch, got nil")
+	}
+
+	if !IsVersionConflict(err) {
+		t.Fatalf("committing conflicting event: want ErrVersionMismatch, got %v", err)
+	}
+
+	events, err := store.GetEvents(ctx, "42", 0)
+	if err != nil {
+		t.Fatalf("loading events: %v", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events after conflicting write, want 1 (the loser must not be persisted)", len(events))
+	}
+}
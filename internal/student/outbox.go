@@ -0,0 +1,239 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"geevly/internal/auth"
+
+	"github.com/Howard3/gosignal"
+)
+
+const outboxSchema = `
+CREATE TABLE IF NOT EXISTS outbox (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	aggregate_id TEXT NOT NULL,
+	event_type   TEXT NOT NULL,
+	version      INTEGER NOT NULL,
+	data         BLOB NOT NULL,
+	timestamp    DATETIME NOT NULL,
+	actor_id     TEXT NOT NULL DEFAULT '',
+	actor_name   TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS outbox_cursors (
+	subscriber TEXT PRIMARY KEY,
+	position   INTEGER NOT NULL DEFAULT 0
+);`
+
+// maxDeliveryAttempts bounds how many times a single outbox row is retried against a subscriber
+// before the dispatcher gives up on this poll and tries again on the next one.
+const maxDeliveryAttempts = 5
+
+// outboxRow is a single committed event awaiting dispatch to subscribers, along with the actor
+// that was attached to the context Save was called with, if any.
+type outboxRow struct {
+	id    int64
+	event gosignal.Event
+	actor auth.Actor
+}
+
+// subscriber is a registered consumer of the outbox, identified by name so its delivery cursor
+// can be tracked independently of every other subscriber.
+type subscriber struct {
+	name    string
+	handler func(context.Context, *gosignal.Event) error
+}
+
+// outboxDispatcher polls the outbox table and delivers new rows to every registered subscriber
+// at-least-once, tracking each subscriber's progress with its own cursor so a failure in one
+// subscriber never blocks or loses delivery to the others.
+type outboxDispatcher struct {
+	db           *sql.DB
+	pollInterval time.Duration
+	maxBackoff   time.Duration
+
+	subscribers []subscriber
+}
+
+func newOutboxDispatcher(db *sql.DB) *outboxDispatcher {
+	return &outboxDispatcher{
+		db:           db,
+		pollInterval: time.Second,
+		maxBackoff:   time.Minute,
+	}
+}
+
+// insertOutboxTx writes evt to the outbox within tx, so it's committed atomically with the
+// event-store append that produced it. Whichever actor is attached to ctx - the request context
+// Repository.Save was called with - is captured alongside it, since subscribers are delivered
+// from the dispatcher's own background context and can't read it off ctx themselves.
+func insertOutboxTx(ctx context.Context, tx *sql.Tx, evt gosignal.Event) error {
+	const q = `INSERT INTO outbox (aggregate_id, event_type, version, data, timestamp, actor_id, actor_name) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	actor, _ := auth.ActorFromContext(ctx)
+
+	if _, err := tx.ExecContext(ctx, q, evt.AggregateID, evt.Type, evt.Version, evt.Data, evt.Timestamp, actor.ID, actor.Name); err != nil {
+		return fmt.Errorf("inserting outbox row for %q: %w", evt.AggregateID, err)
+	}
+
+	return nil
+}
+
+// Subscribe registers handler under name to receive every event committed to the outbox from here
+// on. New read models can be added this way without touching any existing handler.
+func (d *outboxDispatcher) Subscribe(name string, handler func(context.Context, *gosignal.Event) error) {
+	d.subscribers = append(d.subscribers, subscriber{name: name, handler: handler})
+}
+
+// run starts one polling loop per registered subscriber and blocks until ctx is cancelled. Each
+// subscriber polls and delivers independently, so a subscriber stuck retrying a failing delivery -
+// deliverRow's backoff runs on that subscriber's own goroutine - never delays delivery to any
+// other subscriber.
+func (d *outboxDispatcher) run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, sub := range d.subscribers {
+		wg.Add(1)
+
+		go func(sub subscriber) {
+			defer wg.Done()
+			d.runSubscriber(ctx, sub)
+		}(sub)
+	}
+
+	wg.Wait()
+}
+
+// runSubscriber polls the outbox for sub until ctx is cancelled, delivering new rows on each tick.
+func (d *outboxDispatcher) runSubscriber(ctx context.Context, sub subscriber) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.deliverTo(ctx, sub); err != nil {
+				slog.Error("outbox dispatch failed", "subscriber", sub.name, "error", err)
+			}
+		}
+	}
+}
+
+// deliverTo delivers every outbox row after sub's cursor to sub's handler, in order, advancing
+// the cursor one row at a time so a failure only redelivers from the failed row onward.
+func (d *outboxDispatcher) deliverTo(ctx context.Context, sub subscriber) error {
+	position, err := d.cursor(ctx, sub.name)
+	if err != nil {
+		return fmt.Errorf("loading cursor for %q: %w", sub.name, err)
+	}
+
+	rows, err := d.rowsAfter(ctx, position)
+	if err != nil {
+		return fmt.Errorf("loading outbox rows after %d: %w", position, err)
+	}
+
+	for _, row := range rows {
+		if err := d.deliverRow(ctx, sub, row); err != nil {
+			return fmt.Errorf("delivering outbox row %d to %q: %w", row.id, sub.name, err)
+		}
+
+		if err := d.setCursor(ctx, sub.name, row.id); err != nil {
+			return fmt.Errorf("advancing cursor for %q: %w", sub.name, err)
+		}
+	}
+
+	return nil
+}
+
+// deliverRow calls sub's handler for row, retrying with exponential backoff up to
+// maxDeliveryAttempts times before giving up. The actor captured when row was written is
+// reattached to ctx so a handler can recover it via auth.ActorFromContext, the same way it would
+// from a request context.
+func (d *outboxDispatcher) deliverRow(ctx context.Context, sub subscriber, row outboxRow) error {
+	if row.actor != (auth.Actor{}) {
+		ctx = auth.WithActor(ctx, row.actor)
+	}
+
+	backoff := time.Second
+	var err error
+
+	for attempt := 0; attempt < maxDeliveryAttempts; attempt++ {
+		if err = sub.handler(ctx, &row.event); err == nil {
+			return nil
+		}
+
+		if attempt < maxDeliveryAttempts-1 {
+			time.Sleep(backoff)
+			if backoff < d.maxBackoff {
+				backoff *= 2
+			}
+		}
+	}
+
+	return err
+}
+
+func (d *outboxDispatcher) cursor(ctx context.Context, name string) (int64, error) {
+	var position int64
+
+	row := d.db.QueryRowContext(ctx, `SELECT position FROM outbox_cursors WHERE subscriber = ?`, name)
+
+	switch err := row.Scan(&position); {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+
+	return position, nil
+}
+
+func (d *outboxDispatcher) setCursor(ctx context.Context, name string, position int64) error {
+	const q = `
+INSERT INTO outbox_cursors (subscriber, position) VALUES (?, ?)
+ON CONFLICT(subscriber) DO UPDATE SET position = excluded.position`
+
+	_, err := d.db.ExecContext(ctx, q, name, position)
+
+	return err
+}
+
+func (d *outboxDispatcher) rowsAfter(ctx context.Context, position int64) ([]outboxRow, error) {
+	const q = `SELECT id, aggregate_id, event_type, version, data, timestamp, actor_id, actor_name FROM outbox WHERE id > ? ORDER BY id ASC`
+
+	rows, err := d.db.QueryContext(ctx, q, position)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []outboxRow
+
+	for rows.Next() {
+		var row outboxRow
+
+		if err := rows.Scan(&row.id, &row.event.AggregateID, &row.event.Type, &row.event.Version, &row.event.Data, &row.event.Timestamp, &row.actor.ID, &row.actor.Name); err != nil {
+			return nil, err
+		}
+
+		out = append(out, row)
+	}
+
+	return out, rows.Err()
+}
+
+// ReplayFrom resets subscriberName's cursor to fromVersion, letting the dispatcher redeliver
+// every outbox row after that point. This lets a new subscriber rebuild its projection from
+// scratch (fromVersion 0) or catch up a stale one; if the outbox has been truncated before
+// fromVersion, the caller should rebuild from the event store instead.
+func (d *outboxDispatcher) ReplayFrom(ctx context.Context, subscriberName string, fromVersion int64) error {
+	return d.setCursor(ctx, subscriberName, fromVersion)
+}
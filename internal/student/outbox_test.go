@@ -0,0 +1,232 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Howard3/gosignal"
+)
+
+// newOutboxTestDB opens an in-memory sqlite database with the outbox tables created.
+func newOutboxTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("opening test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(outboxSchema); err != nil {
+		t.Fatalf("creating outbox schema: %v", err)
+	}
+
+	return db
+}
+
+// insertTestOutboxRows writes one outbox row per evt, each in its own transaction, mirroring how
+// Repository.Save commits one event at a time.
+func insertTestOutboxRows(t *testing.T, db *sql.DB, events ...gosignal.Event) {
+	t.Helper()
+
+	for _, evt := range events {
+		tx, err := db.BeginTx(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("beginning transaction: %v", err)
+		}
+
+		if err := insertOutboxTx(context.Background(), tx, evt); err != nil {
+			t.Fatalf("inserting outbox row: %v", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("committing outbox row: %v", err)
+		}
+	}
+}
+
+func testEvent(aggID string, version uint) gosignal.Event {
+	return gosignal.Event{
+		AggregateID: aggID,
+		Type:        EVENT_ADD_STUDENT,
+		Version:     version,
+		Data:        []byte("payload"),
+		Timestamp:   time.Unix(1700000000+int64(version), 0).UTC(),
+	}
+}
+
+// TestOutboxDispatcher_DeliverTo_AdvancesCursorInOrder verifies that deliverTo delivers every row
+// after a subscriber's cursor, in order, and leaves the cursor at the last row delivered.
+func TestOutboxDispatcher_DeliverTo_AdvancesCursorInOrder(t *testing.T) {
+	ctx := context.Background()
+	db := newOutboxTestDB(t)
+	insertTestOutboxRows(t, db, testEvent("42", 0), testEvent("42", 1), testEvent("42", 2))
+
+	d := newOutboxDispatcher(db)
+
+	var gotVersions []uint
+
+	d.Subscribe("projector", func(ctx context.Context, evt *gosignal.Event) error {
+		gotVersions = append(gotVersions, evt.Version)
+		return nil
+	})
+
+	if err := d.deliverTo(ctx, d.subscribers[0]); err != nil {
+		t.Fatalf("delivering: %v", err)
+	}
+
+	if want := []uint{0, 1, 2}; !equalUintSlices(gotVersions, want) {
+		t.Fatalf("delivered versions = %v, want %v", gotVersions, want)
+	}
+
+	position, err := d.cursor(ctx, "projector")
+	if err != nil {
+		t.Fatalf("loading cursor: %v", err)
+	}
+
+	if position != 3 {
+		t.Fatalf("cursor = %d, want 3 (the last row's id)", position)
+	}
+
+	// A second delivery with nothing new committed should be a no-op.
+	gotVersions = nil
+
+	if err := d.deliverTo(ctx, d.subscribers[0]); err != nil {
+		t.Fatalf("delivering again: %v", err)
+	}
+
+	if len(gotVersions) != 0 {
+		t.Fatalf("redelivered %v after cursor had already advanced past every row", gotVersions)
+	}
+}
+
+// TestOutboxDispatcher_DeliverRow_RetriesWithBackoffThenSucceeds verifies that deliverRow retries
+// a failing handler call rather than giving up on the first error, and returns nil once the
+// handler succeeds.
+func TestOutboxDispatcher_DeliverRow_RetriesWithBackoffThenSucceeds(t *testing.T) {
+	d := &outboxDispatcher{maxBackoff: time.Second}
+
+	errTemporarilyUnavailable := errors.New("handler temporarily unavailable")
+
+	var calls int
+
+	sub := subscriber{
+		name: "flaky",
+		handler: func(ctx context.Context, evt *gosignal.Event) error {
+			calls++
+			if calls == 1 {
+				return errTemporarilyUnavailable
+			}
+
+			return nil
+		},
+	}
+
+	row := outboxRow{id: 1, event: testEvent("42", 0)}
+
+	if err := d.deliverRow(context.Background(), sub, row); err != nil {
+		t.Fatalf("deliverRow: %v", err)
+	}
+
+	if calls != 2 {
+		t.Fatalf("handler called %d times, want 2 (one failure, then a retry that succeeds)", calls)
+	}
+}
+
+// TestOutboxDispatcher_PerSubscriberIsolation verifies that one subscriber hanging on a delivery
+// doesn't prevent another, independent subscriber from making progress - the whole point of
+// running each subscriber on its own goroutine with its own cursor.
+func TestOutboxDispatcher_PerSubscriberIsolation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	db := newOutboxTestDB(t)
+	insertTestOutboxRows(t, db, testEvent("42", 0))
+
+	d := &outboxDispatcher{db: db, pollInterval: 10 * time.Millisecond, maxBackoff: time.Second}
+
+	d.Subscribe("hung", func(ctx context.Context, evt *gosignal.Event) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	var fastCalls int32
+
+	d.Subscribe("fast", func(ctx context.Context, evt *gosignal.Event) error {
+		atomic.AddInt32(&fastCalls, 1)
+		return nil
+	})
+
+	go d.run(ctx)
+
+	deadline := time.After(2 * time.Second)
+
+	for atomic.LoadInt32(&fastCalls) == 0 {
+		select {
+		case <-deadline:
+			cancel()
+			t.Fatal("fast subscriber never delivered while the other subscriber was hung")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+}
+
+// TestOutboxDispatcher_ReplayFrom verifies that resetting a subscriber's cursor with ReplayFrom
+// causes its next delivery to redeliver rows from that point, for rebuilding a projection from
+// scratch or catching up a stale one.
+func TestOutboxDispatcher_ReplayFrom(t *testing.T) {
+	ctx := context.Background()
+	db := newOutboxTestDB(t)
+	insertTestOutboxRows(t, db, testEvent("42", 0), testEvent("42", 1))
+
+	d := newOutboxDispatcher(db)
+
+	var gotVersions []uint
+
+	d.Subscribe("projector", func(ctx context.Context, evt *gosignal.Event) error {
+		gotVersions = append(gotVersions, evt.Version)
+		return nil
+	})
+
+	if err := d.deliverTo(ctx, d.subscribers[0]); err != nil {
+		t.Fatalf("delivering: %v", err)
+	}
+
+	if want := []uint{0, 1}; !equalUintSlices(gotVersions, want) {
+		t.Fatalf("delivered versions = %v, want %v", gotVersions, want)
+	}
+
+	if err := d.ReplayFrom(ctx, "projector", 0); err != nil {
+		t.Fatalf("replaying from scratch: %v", err)
+	}
+
+	gotVersions = nil
+
+	if err := d.deliverTo(ctx, d.subscribers[0]); err != nil {
+		t.Fatalf("delivering after replay: %v", err)
+	}
+
+	if want := []uint{0, 1}; !equalUintSlices(gotVersions, want) {
+		t.Fatalf("redelivered versions after replay = %v, want %v", gotVersions, want)
+	}
+}
+
+func equalUintSlices(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,39 @@
+package student
+
+import (
+	"context"
+	"fmt"
+)
+
+// upsertStudent writes sd's current state to the students read-model table, insert-or-update by
+// aggregate ID.
+func (r *Repository) upsertStudent(sd *StudentData) error {
+	const q = `
+INSERT INTO students (id, first_name, last_name, status, school_id, version)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET
+	first_name = excluded.first_name,
+	last_name  = excluded.last_name,
+	status     = excluded.status,
+	school_id  = excluded.school_id,
+	version    = excluded.version`
+
+	_, err := r.db.Exec(q, sd.GetID(), sd.data.FirstName, sd.data.LastName, sd.data.Status, sd.data.SchoolId, sd.GetVersion())
+	if err != nil {
+		return fmt.Errorf("upserting student %q: %w", sd.GetID(), err)
+	}
+
+	return nil
+}
+
+// insertStudentCode records code as the lookup code for aggID in the student_codes read-model
+// table.
+func (r *Repository) insertStudentCode(ctx context.Context, aggID uint64, code string) error {
+	const q = `INSERT INTO student_codes (student_id, code) VALUES (?, ?)`
+
+	if _, err := r.db.ExecContext(ctx, q, aggID, code); err != nil {
+		return fmt.Errorf("inserting student code for %d: %w", aggID, err)
+	}
+
+	return nil
+}
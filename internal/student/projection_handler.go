@@ -0,0 +1,74 @@
+package student
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/Howard3/gosignal"
+)
+
+// studentProjectionHandler keeps the students and student_codes read models in sync with the
+// event log, and takes aggregate snapshots according to the repository's snapshot policy.
+type studentProjectionHandler struct {
+	repo *Repository
+}
+
+func (h *studentProjectionHandler) Name() string { return "student-projection" }
+
+// Handle routes evt to the projection step for its type. It's registered as an EventHandler and
+// runs asynchronously off the outbox dispatcher, so a returned error is retried with backoff
+// rather than just logged.
+func (h *studentProjectionHandler) Handle(ctx context.Context, evt *gosignal.Event) error {
+	id, err := strconv.ParseUint(evt.AggregateID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse aggregate ID: %w", err)
+	}
+
+	switch evt.Type {
+	case EVENT_ADD_STUDENT, EVENT_UPDATE_STUDENT, EVENT_ENROLL_STUDENT, EVENT_UNENROLL_STUDENT, EVENT_SET_STUDENT_STATUS:
+		return h.upsertStudent(ctx, id)
+	case EVENT_SET_LOOKUP_CODE:
+		return h.generateCode(ctx, id)
+	default:
+		slog.Warn("no projection registered for event type", "type", evt.Type)
+		return nil
+	}
+}
+
+// upsertStudent reloads the student aggregate and projects its current state to the students
+// read model; it's functionally the same whether the event was a create or an update, since it's
+// always the full current state that's projected.
+func (h *studentProjectionHandler) upsertStudent(ctx context.Context, aggID uint64) error {
+	student, err := h.repo.loadStudent(ctx, aggID)
+	if err != nil {
+		return fmt.Errorf("failed to load student: %w", err)
+	}
+
+	if err := h.repo.upsertStudent(student); err != nil {
+		return fmt.Errorf("failed to upsert student: %w", err)
+	}
+
+	h.repo.maybeSnapshot(ctx, student)
+
+	return nil
+}
+
+func (h *studentProjectionHandler) generateCode(ctx context.Context, aggID uint64) error {
+	student, err := h.repo.loadStudent(ctx, aggID)
+	if err != nil {
+		return fmt.Errorf("failed to load student: %w", err)
+	}
+
+	code := student.data.CodeUniqueId
+	if len(code) == 0 {
+		return fmt.Errorf("code is empty")
+	}
+
+	if err := h.repo.insertStudentCode(ctx, aggID, code); err != nil {
+		return fmt.Errorf("failed to insert student code: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/Howard3/gosignal"
+)
+
+// EventStore is the subset of the event-sourcing event store that the student repository depends
+// on for loading and persisting StudentData's event history.
+type EventStore interface {
+	// GetEvents returns the events for aggID with Version greater than afterVersion, in order.
+	GetEvents(ctx context.Context, aggID string, afterVersion uint) ([]gosignal.Event, error)
+	// AppendEventTx persists evt for aggID within tx, keyed on (aggID, expectedVersion).
+	// Implementations must CAS the insert against a unique (aggregate_id, version) constraint and
+	// return ErrVersionMismatch if expectedVersion doesn't match the version actually persisted.
+	// Running within the caller's transaction lets Repository.Save commit the event-store append
+	// and the outbox row atomically.
+	AppendEventTx(ctx context.Context, tx *sql.Tx, aggID string, evt gosignal.Event, expectedVersion uint) error
+}
+
+// Repository loads and persists StudentData aggregates, and maintains the read models that are
+// projected from their events.
+type Repository struct {
+	db        *sql.DB
+	store     EventStore
+	snapshots SnapshotStore
+	policy    snapshotPolicy
+}
+
+// NewRepository creates a Repository backed by store for event persistence and, if snapshots is
+// non-nil, snapshots for aggregate snapshotting. A nil snapshots disables snapshotting entirely,
+// falling back to replaying the full event history on every load.
+func NewRepository(db *sql.DB, store EventStore, snapshots SnapshotStore) *Repository {
+	return &Repository{
+		db:        db,
+		store:     store,
+		snapshots: snapshots,
+		policy:    snapshotPolicy{every: 100},
+	}
+}
+
+// loadStudent loads the StudentData aggregate for aggID, seeding it from the latest snapshot (if
+// one exists) before replaying any events committed after the snapshot was taken.
+func (r *Repository) loadStudent(ctx context.Context, aggID uint64) (*StudentData, error) {
+	id := strconv.FormatUint(aggID, 10)
+	sd := &StudentData{}
+	sd.ID = id
+
+	afterVersion := uint(0)
+
+	if r.snapshots != nil {
+		state, version, err := r.snapshots.Load(ctx, id)
+		switch {
+		case errors.Is(err, ErrSnapshotNotFound):
+			// no snapshot yet, replay from the start
+		case err != nil:
+			return nil, fmt.Errorf("loading snapshot for student %q: %w", id, err)
+		default:
+			if err := sd.ImportState(state); err != nil {
+				return nil, fmt.Errorf("importing snapshot for student %q: %w", id, err)
+			}
+			sd.Version = version
+			afterVersion = version
+		}
+	}
+
+	events, err := r.store.GetEvents(ctx, id, afterVersion)
+	if err != nil {
+		return nil, fmt.Errorf("loading events for student %q: %w", id, err)
+	}
+
+	for _, evt := range events {
+		if err := sd.Apply(evt); err != nil {
+			return nil, fmt.Errorf("replaying event for student %q: %w", id, err)
+		}
+	}
+
+	return sd, nil
+}
+
+// Save persists evt for sd, enforcing optimistic concurrency: evt.Version is taken as the caller's
+// expected version, and Save returns ErrVersionMismatch if another writer has already committed a
+// different version for this aggregate. The event-store append and the outbox row that drives
+// eventHandlers' async projections are written in a single transaction, so a projection can never
+// observe an event that didn't actually commit.
+func (r *Repository) Save(ctx context.Context, sd *StudentData, evt *gosignal.Event) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction for student %q: %w", sd.GetID(), err)
+	}
+	defer tx.Rollback()
+
+	if err := r.store.AppendEventTx(ctx, tx, sd.GetID(), *evt, evt.Version); err != nil {
+		return fmt.Errorf("saving event for student %q: %w", sd.GetID(), err)
+	}
+
+	if err := insertOutboxTx(ctx, tx, *evt); err != nil {
+		return fmt.Errorf("saving event for student %q: %w", sd.GetID(), err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing event for student %q: %w", sd.GetID(), err)
+	}
+
+	return nil
+}
+
+// maxSaveAttempts bounds how many times WithRetry will reload and rerun a command before giving
+// up on a persistent version conflict.
+const maxSaveAttempts = 3
+
+// WithRetry loads the student aggregate identified by aggID, runs fn against it, and saves the
+// event fn returns. If Save reports ErrVersionMismatch - meaning another writer committed first -
+// it reloads the aggregate and reruns fn against the fresh state, up to maxSaveAttempts times.
+func (r *Repository) WithRetry(ctx context.Context, aggID uint64, fn func(sd *StudentData) (*gosignal.Event, error)) error {
+	var lastErr error
+
+	for attempt := 0; attempt < maxSaveAttempts; attempt++ {
+		sd, err := r.loadStudent(ctx, aggID)
+		if err != nil {
+			return err
+		}
+
+		evt, err := fn(sd)
+		if err != nil {
+			return err
+		}
+
+		if err := r.Save(ctx, sd, evt); err != nil {
+			var mismatch ErrVersionMismatch
+			if !errors.As(err, &mismatch) {
+				return err
+			}
+
+			lastErr = err
+			continue
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("student %d: exceeded %d attempts: %w", aggID, maxSaveAttempts, lastErr)
+}
+
+// IsVersionConflict reports whether err is (or wraps) an ErrVersionMismatch, for callers such as
+// the HTTP layer that need to map it onto a 409 Conflict response.
+//
+// This package has no HTTP layer of its own yet - this repo doesn't have one at all - so wiring
+// this into an actual 409 response is left to whatever handler package eventually calls into
+// Repository.
+func IsVersionConflict(err error) bool {
+	var mismatch ErrVersionMismatch
+	return errors.As(err, &mismatch)
+}
+
+// RebuildSnapshot discards any existing snapshot for aggID and rebuilds it from a full replay of
+// the event log, for administrative use when a snapshot is suspected to be stale or corrupt.
+func (r *Repository) RebuildSnapshot(ctx context.Context, aggID uint64) error {
+	if r.snapshots == nil {
+		return fmt.Errorf("rebuilding snapshot for student %d: no snapshot store configured", aggID)
+	}
+
+	id := strconv.FormatUint(aggID, 10)
+
+	sd := &StudentData{}
+	sd.ID = id
+
+	events, err := r.store.GetEvents(ctx, id, 0)
+	if err != nil {
+		return fmt.Errorf("loading events for student %q: %w", id, err)
+	}
+
+	for _, evt := range events {
+		if err := sd.Apply(evt); err != nil {
+			return fmt.Errorf("replaying event for student %q: %w", id, err)
+		}
+	}
+
+	return r.saveSnapshot(ctx, sd)
+}
+
+// maybeSnapshot takes a snapshot of sd if the repository's snapshot policy calls for one at its
+// current version. It's invoked from studentProjectionHandler.Handle after an event has been
+// successfully projected.
+func (r *Repository) maybeSnapshot(ctx context.Context, sd *StudentData) {
+	if r.snapshots == nil || !r.policy.shouldSnapshot(sd.GetVersion()) {
+		return
+	}
+
+	if err := r.saveSnapshot(ctx, sd); err != nil {
+		slog.Error("failed to save student snapshot", "aggregate_id", sd.GetID(), "error", err)
+	}
+}
+
+func (r *Repository) saveSnapshot(ctx context.Context, sd *StudentData) error {
+	state, err := sd.ExportState()
+	if err != nil {
+		return fmt.Errorf("exporting state for student %q: %w", sd.GetID(), err)
+	}
+
+	if err := r.snapshots.Save(ctx, sd.GetID(), state, sd.GetVersion()); err != nil {
+		return fmt.Errorf("saving snapshot for student %q: %w", sd.GetID(), err)
+	}
+
+	return nil
+}
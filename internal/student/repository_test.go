@@ -0,0 +1,284 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	student "geevly/events/gen/proto/go"
+
+	"github.com/Howard3/gosignal"
+	"google.golang.org/protobuf/proto"
+
+	_ "modernc.org/sqlite"
+)
+
+// inMemoryEventStore is a minimal EventStore for tests, keeping events in memory keyed by
+// aggregate ID.
+type inMemoryEventStore struct {
+	events map[string][]gosignal.Event
+}
+
+func newInMemoryEventStore() *inMemoryEventStore {
+	return &inMemoryEventStore{events: map[string][]gosignal.Event{}}
+}
+
+func (s *inMemoryEventStore) GetEvents(ctx context.Context, aggID string, afterVersion uint) ([]gosignal.Event, error) {
+	var out []gosignal.Event
+
+	for _, evt := range s.events[aggID] {
+		if evt.Version > afterVersion {
+			out = append(out, evt)
+		}
+	}
+
+	return out, nil
+}
+
+func (s *inMemoryEventStore) AppendEventTx(ctx context.Context, tx *sql.Tx, aggID string, evt gosignal.Event, expectedVersion uint) error {
+	s.events[aggID] = append(s.events[aggID], evt)
+	return nil
+}
+
+// fakeSnapshotStore is a single-slot SnapshotStore for tests.
+type fakeSnapshotStore struct {
+	state   []byte
+	version uint
+	set     bool
+}
+
+func (s *fakeSnapshotStore) Load(ctx context.Context, aggID string) ([]byte, uint, error) {
+	if !s.set {
+		return nil, 0, ErrSnapshotNotFound
+	}
+
+	return s.state, s.version, nil
+}
+
+func (s *fakeSnapshotStore) Save(ctx context.Context, aggID string, state []byte, version uint) error {
+	s.state, s.version, s.set = state, version, true
+	return nil
+}
+
+// buildStudentEvents constructs a realistic AddStudent -> UpdateStudent -> EnrollStudent event
+// sequence for aggregate ID "42" by driving StudentData's own command methods, the same way a
+// real caller would, and returns both the events and the fully-applied aggregate they produce.
+func buildStudentEvents(t *testing.T) ([]gosignal.Event, *StudentData) {
+	t.Helper()
+
+	sd := &StudentData{}
+	sd.ID = "42"
+
+	evt0, err := sd.CreateStudent(&student.AddStudentEvent{
+		FirstName: "Ada", LastName: "Lovelace", DateOfBirth: "1815-12-10", SchoolId: "1",
+	})
+	if err != nil {
+		t.Fatalf("creating student: %v", err)
+	}
+
+	if sd.GetVersion() != 0 {
+		t.Fatalf("version after create = %d, want 0", sd.GetVersion())
+	}
+
+	evt1, err := sd.UpdateStudent(&student.UpdateStudentEvent{
+		FirstName: "Ada", LastName: "King", DateOfBirth: "1815-12-10", SchoolId: "1",
+	}, sd.GetVersion()+1)
+	if err != nil {
+		t.Fatalf("updating student: %v", err)
+	}
+
+	if sd.GetVersion() != 1 {
+		t.Fatalf("version after update = %d, want 1", sd.GetVersion())
+	}
+
+	evt2, err := sd.EnrollStudent(&student.EnrollStudentEvent{
+		SchoolId: "2", DateOfEnrollment: "2024-01-01",
+	}, sd.GetVersion()+1)
+	if err != nil {
+		t.Fatalf("enrolling student: %v", err)
+	}
+
+	if sd.GetVersion() != 2 {
+		t.Fatalf("version after enroll = %d, want 2", sd.GetVersion())
+	}
+
+	return []gosignal.Event{*evt0, *evt1, *evt2}, sd
+}
+
+// TestLoadStudent_SnapshotEquivalence verifies that loadStudent produces the same aggregate state
+// and version whether it seeds from a snapshot and replays only the events after it, or replays
+// the full event history from scratch.
+func TestLoadStudent_SnapshotEquivalence(t *testing.T) {
+	ctx := context.Background()
+
+	events, built := buildStudentEvents(t)
+
+	store := newInMemoryEventStore()
+	store.events[built.GetID()] = events
+
+	withoutSnapshotRepo := &Repository{store: store}
+
+	withoutSnapshot, err := withoutSnapshotRepo.loadStudent(ctx, 42)
+	if err != nil {
+		t.Fatalf("loading without snapshot: %v", err)
+	}
+
+	// Seed a snapshot at the state immediately after the first event, so loadStudent only has to
+	// replay the remaining two events.
+	sdAtFirstEvent := &StudentData{}
+	sdAtFirstEvent.ID = built.GetID()
+
+	if err := sdAtFirstEvent.Apply(events[0]); err != nil {
+		t.Fatalf("replaying first event: %v", err)
+	}
+
+	snapshotState, err := sdAtFirstEvent.ExportState()
+	if err != nil {
+		t.Fatalf("exporting snapshot state: %v", err)
+	}
+
+	withSnapshotRepo := &Repository{
+		store:     store,
+		snapshots: &fakeSnapshotStore{state: snapshotState, version: sdAtFirstEvent.GetVersion(), set: true},
+	}
+
+	withSnapshot, err := withSnapshotRepo.loadStudent(ctx, 42)
+	if err != nil {
+		t.Fatalf("loading with snapshot: %v", err)
+	}
+
+	if withoutSnapshot.GetVersion() != withSnapshot.GetVersion() {
+		t.Fatalf("version mismatch: without snapshot = %d, with snapshot = %d",
+			withoutSnapshot.GetVersion(), withSnapshot.GetVersion())
+	}
+
+	if !proto.Equal(withoutSnapshot.data, withSnapshot.data) {
+		t.Fatalf("aggregate state differs between snapshot and full replay:\nwithout snapshot: %+v\nwith snapshot:    %+v",
+			withoutSnapshot.data, withSnapshot.data)
+	}
+}
+
+// TestStudentData_VersionAccounting verifies that each successful Apply through SafeApply
+// advances StudentData's version to match the applied event's own version, so a caller's
+// expectedVersion bookkeeping stays in lockstep with the aggregate.
+func TestStudentData_VersionAccounting(t *testing.T) {
+	events, built := buildStudentEvents(t)
+
+	if got, want := built.GetVersion(), uint(2); got != want {
+		t.Fatalf("final version = %d, want %d", got, want)
+	}
+
+	sd := &StudentData{}
+	sd.ID = built.GetID()
+
+	for i, evt := range events {
+		if err := sd.Apply(evt); err != nil {
+			t.Fatalf("applying event %d: %v", i, err)
+		}
+
+		if sd.GetVersion() != evt.Version {
+			t.Fatalf("after applying event %d: version = %d, want %d (the event's own version)",
+				i, sd.GetVersion(), evt.Version)
+		}
+	}
+}
+
+// flakyEventStore wraps an inMemoryEventStore but fails AppendEventTx with ErrVersionMismatch for
+// its first failUntil calls, regardless of the expected version passed in, simulating a
+// concurrent writer that keeps winning the race against Repository.WithRetry.
+type flakyEventStore struct {
+	*inMemoryEventStore
+	calls     int
+	failUntil int
+}
+
+func (s *flakyEventStore) AppendEventTx(ctx context.Context, tx *sql.Tx, aggID string, evt gosignal.Event, expectedVersion uint) error {
+	s.calls++
+
+	if s.calls <= s.failUntil {
+		return ErrVersionMismatch{Expected: expectedVersion, Actual: expectedVersion + 1}
+	}
+
+	return s.inMemoryEventStore.AppendEventTx(ctx, tx, aggID, evt, expectedVersion)
+}
+
+// newRepositoryTestDB opens an in-memory sqlite database with the outbox table created, the same
+// schema Repository.Save's insertOutboxTx writes through.
+func newRepositoryTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("opening test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(outboxSchema); err != nil {
+		t.Fatalf("creating outbox schema: %v", err)
+	}
+
+	return db
+}
+
+// TestRepository_WithRetry_ReloadsAndRetriesOnVersionConflict verifies that WithRetry reruns fn
+// against a freshly loaded aggregate each time Save reports ErrVersionMismatch, rather than giving
+// up after the first conflict, and succeeds once the underlying store stops conflicting.
+func TestRepository_WithRetry_ReloadsAndRetriesOnVersionConflict(t *testing.T) {
+	ctx := context.Background()
+
+	store := &flakyEventStore{inMemoryEventStore: newInMemoryEventStore(), failUntil: maxSaveAttempts - 1}
+	repo := &Repository{db: newRepositoryTestDB(t), store: store}
+
+	var calls int
+
+	err := repo.WithRetry(ctx, 42, func(sd *StudentData) (*gosignal.Event, error) {
+		calls++
+		return sd.CreateStudent(&student.AddStudentEvent{
+			FirstName: "Ada", LastName: "Lovelace", DateOfBirth: "1815-12-10", SchoolId: "1",
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithRetry: %v", err)
+	}
+
+	if calls != maxSaveAttempts {
+		t.Fatalf("fn called %d times, want %d (failUntil conflicts plus the final successful attempt)",
+			calls, maxSaveAttempts)
+	}
+
+	events, err := store.GetEvents(ctx, "42", 0)
+	if err != nil {
+		t.Fatalf("loading events: %v", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("got %d persisted events, want exactly 1 (only the attempt that didn't conflict)", len(events))
+	}
+}
+
+// TestRepository_WithRetry_GivesUpAfterMaxAttempts verifies that WithRetry stops retrying once
+// maxSaveAttempts is exhausted and returns an error wrapping the last ErrVersionMismatch, rather
+// than retrying forever against a writer that never stops winning the race.
+func TestRepository_WithRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+
+	store := &flakyEventStore{inMemoryEventStore: newInMemoryEventStore(), failUntil: maxSaveAttempts}
+	repo := &Repository{db: newRepositoryTestDB(t), store: store}
+
+	err := repo.WithRetry(ctx, 42, func(sd *StudentData) (*gosignal.Event, error) {
+		return sd.CreateStudent(&student.AddStudentEvent{
+			FirstName: "Ada", LastName: "Lovelace", DateOfBirth: "1815-12-10", SchoolId: "1",
+		})
+	})
+	if err == nil {
+		t.Fatal("WithRetry: want error after exhausting retries, got nil")
+	}
+
+	if !IsVersionConflict(err) {
+		t.Fatalf("WithRetry error = %v, want it to wrap ErrVersionMismatch", err)
+	}
+
+	if store.calls != maxSaveAttempts {
+		t.Fatalf("AppendEventTx called %d times, want %d (bounded by maxSaveAttempts)", store.calls, maxSaveAttempts)
+	}
+}
@@ -0,0 +1,81 @@
+package student
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrSnapshotNotFound is returned by a SnapshotStore when no snapshot exists for an aggregate.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// SnapshotStore persists point-in-time serialisations of an aggregate so that loadStudent doesn't
+// need to replay the full event history on every read.
+type SnapshotStore interface {
+	// Load returns the most recent snapshot for aggID along with the aggregate version it was
+	// taken at. It returns ErrSnapshotNotFound if no snapshot has been saved yet.
+	Load(ctx context.Context, aggID string) (state []byte, version uint, err error)
+	// Save stores state as the snapshot for aggID at version, replacing any prior snapshot.
+	Save(ctx context.Context, aggID string, state []byte, version uint) error
+}
+
+// sqliteSnapshotStore is a SnapshotStore backed by a sqlite table, stored alongside the event
+// store's own tables.
+type sqliteSnapshotStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteSnapshotStore creates a SnapshotStore backed by the given sqlite database. The caller
+// is responsible for running the schema migration that creates the student_snapshots table.
+func NewSQLiteSnapshotStore(db *sql.DB) SnapshotStore {
+	return &sqliteSnapshotStore{db: db}
+}
+
+const sqliteSnapshotSchema = `
+CREATE TABLE IF NOT EXISTS student_snapshots (
+	aggregate_id TEXT PRIMARY KEY,
+	version      INTEGER NOT NULL,
+	state        BLOB NOT NULL,
+	updated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
+
+func (s *sqliteSnapshotStore) Load(ctx context.Context, aggID string) ([]byte, uint, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT state, version FROM student_snapshots WHERE aggregate_id = ?`, aggID)
+
+	var state []byte
+	var version uint
+
+	switch err := row.Scan(&state, &version); {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, 0, ErrSnapshotNotFound
+	case err != nil:
+		return nil, 0, fmt.Errorf("querying snapshot for %q: %w", aggID, err)
+	}
+
+	return state, version, nil
+}
+
+func (s *sqliteSnapshotStore) Save(ctx context.Context, aggID string, state []byte, version uint) error {
+	const q = `
+INSERT INTO student_snapshots (aggregate_id, version, state, updated_at)
+VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(aggregate_id) DO UPDATE SET version = excluded.version, state = excluded.state, updated_at = excluded.updated_at`
+
+	if _, err := s.db.ExecContext(ctx, q, aggID, version, state); err != nil {
+		return fmt.Errorf("saving snapshot for %q: %w", aggID, err)
+	}
+
+	return nil
+}
+
+// snapshotPolicy decides whether loadStudent's caller should take a fresh snapshot after
+// successfully projecting an event.
+type snapshotPolicy struct {
+	every uint // take a snapshot every `every` events; 0 disables automatic snapshotting
+}
+
+// shouldSnapshot reports whether a snapshot should be taken for an aggregate now at version.
+func (p snapshotPolicy) shouldSnapshot(version uint) bool {
+	return p.every > 0 && version%p.every == 0
+}
@@ -1,4 +1,4 @@
-package main
+package student
 
 import (
 	"errors"
@@ -14,12 +14,24 @@ import (
 var ErrEventNotFound = fmt.Errorf("event not found")
 var ErrApplyingEvent = fmt.Errorf("error applying event")
 var ErrMarshallingEvent = fmt.Errorf("error marshalling event")
-var ErrVersionMismatch = fmt.Errorf("version mismatch")
+
+// ErrVersionMismatch is returned when a command's expected version doesn't match the version
+// actually persisted for the aggregate, meaning another writer got there first.
+type ErrVersionMismatch struct {
+	Expected uint
+	Actual   uint
+}
+
+func (e ErrVersionMismatch) Error() string {
+	return fmt.Sprintf("version mismatch: expected %d, got %d", e.Expected, e.Actual)
+}
 
 const EVENT_ADD_STUDENT = "AddStudent"
 const EVENT_SET_STUDENT_STATUS = "SetStudentStatus"
 const EVENT_UPDATE_STUDENT = "UpdateStudent"
 const EVENT_ENROLL_STUDENT = "EnrollStudent"
+const EVENT_UNENROLL_STUDENT = "UnenrollStudent"
+const EVENT_SET_LOOKUP_CODE = "SetLookupCode"
 
 type wrappedEvent struct {
 	event gosignal.Event
@@ -66,6 +78,12 @@ func (sa *StudentData) routeEvent(evt gosignal.Event) (err error) {
 	case EVENT_ENROLL_STUDENT:
 		eventData = &student.EnrollStudentEvent{}
 		handler = sa.HandleEnrollStudent
+	case EVENT_UNENROLL_STUDENT:
+		eventData = &student.UnenrollStudentEvent{}
+		handler = sa.HandleUnenrollStudent
+	case EVENT_SET_LOOKUP_CODE:
+		eventData = &student.SetLookupCodeEvent{}
+		handler = sa.HandleSetLookupCode
 	default:
 		return ErrEventNotFound
 	}
@@ -79,20 +97,42 @@ func (sa *StudentData) routeEvent(evt gosignal.Event) (err error) {
 	return handler(wevt)
 }
 
+// CreateStudent applies the initial AddStudent event. It always targets version 0, since a
+// student aggregate can only be created once; Repository.Save rejects it with ErrVersionMismatch
+// if a student already exists at this ID.
 func (sa *StudentData) CreateStudent(student *student.AddStudentEvent) (*gosignal.Event, error) {
 	return sa.ApplyEvent(StudentEvent{eventType: EVENT_ADD_STUDENT, data: student, version: 0})
 }
 
-func (sa *StudentData) SetStudentStatus(status *student.SetStudentStatusEvent) (*gosignal.Event, error) {
-	return sa.ApplyEvent(StudentEvent{eventType: EVENT_SET_STUDENT_STATUS, data: status, version: uint(status.Version)})
+// SetStudentStatus applies a status change, expected to land at expectedVersion. The caller is
+// expected to have loaded the aggregate at expectedVersion-1; Repository.Save rejects the
+// resulting event with ErrVersionMismatch if that's no longer the persisted version.
+func (sa *StudentData) SetStudentStatus(status *student.SetStudentStatusEvent, expectedVersion uint) (*gosignal.Event, error) {
+	return sa.ApplyEvent(StudentEvent{eventType: EVENT_SET_STUDENT_STATUS, data: status, version: expectedVersion})
 }
 
-func (sa *StudentData) UpdateStudent(upd *student.UpdateStudentEvent) (*gosignal.Event, error) {
-	return sa.ApplyEvent(StudentEvent{eventType: EVENT_UPDATE_STUDENT, data: upd, version: uint(upd.Version)})
+// UpdateStudent applies a profile update, expected to land at expectedVersion. See
+// SetStudentStatus for the optimistic-concurrency contract.
+func (sa *StudentData) UpdateStudent(upd *student.UpdateStudentEvent, expectedVersion uint) (*gosignal.Event, error) {
+	return sa.ApplyEvent(StudentEvent{eventType: EVENT_UPDATE_STUDENT, data: upd, version: expectedVersion})
 }
 
-func (sa *StudentData) EnrollStudent(enrollment *student.EnrollStudentEvent) (*gosignal.Event, error) {
-	return sa.ApplyEvent(StudentEvent{eventType: EVENT_ENROLL_STUDENT, data: enrollment, version: uint(enrollment.Version)})
+// EnrollStudent applies an enrollment change, expected to land at expectedVersion. See
+// SetStudentStatus for the optimistic-concurrency contract.
+func (sa *StudentData) EnrollStudent(enrollment *student.EnrollStudentEvent, expectedVersion uint) (*gosignal.Event, error) {
+	return sa.ApplyEvent(StudentEvent{eventType: EVENT_ENROLL_STUDENT, data: enrollment, version: expectedVersion})
+}
+
+// UnenrollStudent applies an unenrollment, expected to land at expectedVersion. See
+// SetStudentStatus for the optimistic-concurrency contract.
+func (sa *StudentData) UnenrollStudent(unenrollment *student.UnenrollStudentEvent, expectedVersion uint) (*gosignal.Event, error) {
+	return sa.ApplyEvent(StudentEvent{eventType: EVENT_UNENROLL_STUDENT, data: unenrollment, version: expectedVersion})
+}
+
+// SetLookupCode applies a newly generated lookup code, expected to land at expectedVersion. See
+// SetStudentStatus for the optimistic-concurrency contract.
+func (sa *StudentData) SetLookupCode(code *student.SetLookupCodeEvent, expectedVersion uint) (*gosignal.Event, error) {
+	return sa.ApplyEvent(StudentEvent{eventType: EVENT_SET_LOOKUP_CODE, data: code, version: expectedVersion})
 }
 
 // HandleSetStudentStatus handles the SetStudentStatus event
@@ -155,6 +195,29 @@ func (sa *StudentData) HandleEnrollStudent(evt wrappedEvent) error {
 	return nil
 }
 
+func (sa *StudentData) HandleUnenrollStudent(evt wrappedEvent) error {
+	if sa.data == nil {
+		return fmt.Errorf("student not found")
+	}
+
+	sa.data.SchoolId = ""
+	sa.data.DateOfEnrollment = ""
+
+	return nil
+}
+
+func (sa *StudentData) HandleSetLookupCode(evt wrappedEvent) error {
+	data := evt.data.(*student.SetLookupCodeEvent)
+
+	if sa.data == nil {
+		return fmt.Errorf("student not found")
+	}
+
+	sa.data.CodeUniqueId = data.Code
+
+	return nil
+}
+
 // StudentEvent is a struct that holds the event type and the data
 type StudentEvent struct {
 	eventType string
@@ -0,0 +1,35 @@
+package student
+
+import (
+	"context"
+	"fmt"
+
+	"geevly/internal/webhook"
+
+	"github.com/Howard3/gosignal"
+)
+
+// WebhookHandler fans every student event out to webhook.Repository.Dispatch, which enqueues a
+// signed delivery for each subscription whose event-type filter matches. Because Dispatch is
+// driven by evt.Type rather than a hard-coded list, EVENT_SET_LOOKUP_CODE, EVENT_ENROLL_STUDENT,
+// EVENT_SET_STUDENT_STATUS and any future student event type are externally observable as soon as
+// a subscription asks for them, with no change needed here.
+type WebhookHandler struct {
+	webhooks *webhook.Repository
+}
+
+// NewWebhookHandler creates a WebhookHandler that dispatches through webhooks.
+func NewWebhookHandler(webhooks *webhook.Repository) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks}
+}
+
+func (h *WebhookHandler) Name() string { return "webhooks" }
+
+// Handle dispatches evt to every matching webhook subscription. The event's (aggregate ID,
+// version) pair is used as its delivery ID, since the student event stream has no sequence number
+// of its own.
+func (h *WebhookHandler) Handle(ctx context.Context, evt *gosignal.Event) error {
+	eventID := fmt.Sprintf("%s:%d", evt.AggregateID, evt.Version)
+
+	return h.webhooks.Dispatch(ctx, eventID, evt.Type, evt.AggregateID, evt.Version, evt.Timestamp, evt.Data)
+}
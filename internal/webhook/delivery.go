@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Delivery status values, persisted in webhook_deliveries.status.
+const (
+	StatusPending    = "pending"
+	StatusDelivered  = "delivered"
+	StatusDeadLetter = "dead_letter"
+)
+
+// ErrDeliveryNotFound is returned when a delivery lookup finds no matching row.
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+// Delivery is one attempted (or pending) POST of an event to a subscription, as returned by
+// Repository.ListDeliveries.
+type Delivery struct {
+	ID             uint64
+	SubscriptionID uint64
+	AggregateID    string
+	EventType      string
+	Version        uint
+	Timestamp      time.Time
+	Body           []byte
+	Status         string
+	Attempts       int
+	LastError      string
+	NextAttemptAt  time.Time
+}
+
+const deliverySchema = `
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id                      INTEGER PRIMARY KEY AUTOINCREMENT,
+	subscription_id         INTEGER NOT NULL,
+	aggregate_id            TEXT NOT NULL,
+	event_type              TEXT NOT NULL,
+	version                 INTEGER NOT NULL,
+	timestamp               DATETIME NOT NULL,
+	body                     BLOB NOT NULL,
+	status                  TEXT NOT NULL DEFAULT 'pending',
+	attempts                INTEGER NOT NULL DEFAULT 0,
+	max_attempts            INTEGER NOT NULL,
+	initial_backoff_seconds INTEGER NOT NULL,
+	last_error              TEXT NOT NULL DEFAULT '',
+	next_attempt_at         DATETIME NOT NULL
+);`
+
+// eventBody is the JSON shape POSTed to a subscriber's URL. id is the (aggregate_id, version)
+// pair that uniquely identifies the event, since the student event stream has no delivery-wide
+// sequence number of its own.
+type eventBody struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Version     uint      `json:"version"`
+	AggregateID string    `json:"aggregate_id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Data        []byte    `json:"data"`
+}
+
+// deliveryTimeout bounds how long a single webhook POST is allowed to hang before attempt gives
+// up on it and moves on, so one unresponsive subscriber endpoint can't stall delivery.
+const deliveryTimeout = 10 * time.Second
+
+// Repository manages webhook subscriptions and their deliveries, and runs the background
+// dispatcher that drives delivery. Construct with NewRepository.
+type Repository struct {
+	db           *sql.DB
+	client       *http.Client
+	pollInterval time.Duration
+}
+
+// NewRepository creates a Repository backed by db and starts its background delivery dispatcher.
+// The caller is responsible for running the schema migrations that create the
+// webhook_subscriptions and webhook_deliveries tables.
+func NewRepository(db *sql.DB) *Repository {
+	r := &Repository{
+		db:           db,
+		client:       &http.Client{Timeout: deliveryTimeout},
+		pollInterval: time.Second,
+	}
+
+	go r.runDispatcher(context.Background())
+
+	return r
+}
+
+// Dispatch is called for every committed student event - regardless of type, so a new event type
+// becomes externally observable without touching this package - and enqueues one pending delivery
+// per active subscription whose event-type filter matches eventType. eventID identifies the event
+// in the delivered body; callers typically derive it from the (aggregate ID, version) pair, since
+// that's what uniquely identifies a student event.
+func (r *Repository) Dispatch(ctx context.Context, eventID, eventType, aggregateID string, version uint, timestamp time.Time, data []byte) error {
+	subs, err := r.matchingSubscriptions(ctx, eventType)
+	if err != nil {
+		return fmt.Errorf("finding webhook subscriptions for %q: %w", eventType, err)
+	}
+
+	for _, sub := range subs {
+		body, err := json.Marshal(eventBody{
+			ID:          eventID,
+			Type:        eventType,
+			Version:     version,
+			AggregateID: aggregateID,
+			Timestamp:   timestamp,
+			Data:        data,
+		})
+		if err != nil {
+			return fmt.Errorf("marshalling webhook body for subscription %d: %w", sub.ID, err)
+		}
+
+		if err := r.enqueueDelivery(ctx, sub, aggregateID, eventType, version, timestamp, body); err != nil {
+			return fmt.Errorf("enqueuing webhook delivery for subscription %d: %w", sub.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) enqueueDelivery(ctx context.Context, sub Subscription, aggregateID, eventType string, version uint, timestamp time.Time, body []byte) error {
+	const q = `
+INSERT INTO webhook_deliveries
+	(subscription_id, aggregate_id, event_type, version, timestamp, body, max_attempts, initial_backoff_seconds, next_attempt_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.ExecContext(ctx, q, sub.ID, aggregateID, eventType, version, timestamp, body,
+		sub.Retry.MaxAttempts, int64(sub.Retry.InitialBackoff/time.Second), timestamp)
+
+	return err
+}
+
+// ListDeliveries returns the most recent deliveries, optionally filtered to a single
+// subscription. Pass subscriptionID 0 to list across every subscription.
+//
+// This, together with Redeliver, is the data access side of the admin endpoint the request asked
+// for; this repo has no HTTP layer yet for that endpoint to live in, so exposing it is left to
+// whatever handler package eventually calls into Repository.
+func (r *Repository) ListDeliveries(ctx context.Context, subscriptionID uint64, page Pagination) ([]Delivery, error) {
+	const q = `
+SELECT id, subscription_id, aggregate_id, event_type, version, timestamp, body, status, attempts, last_error, next_attempt_at
+FROM webhook_deliveries
+WHERE (? = 0 OR subscription_id = ?)
+ORDER BY id DESC
+LIMIT ? OFFSET ?`
+
+	rows, err := r.db.QueryContext(ctx, q, subscriptionID, subscriptionID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+
+	for rows.Next() {
+		var d Delivery
+
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.AggregateID, &d.EventType, &d.Version, &d.Timestamp,
+			&d.Body, &d.Status, &d.Attempts, &d.LastError, &d.NextAttemptAt); err != nil {
+			return nil, fmt.Errorf("scanning webhook delivery row: %w", err)
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Pagination bounds a single page of a listing.
+type Pagination struct {
+	Limit  int
+	Offset int
+}
+
+// Redeliver resets deliveryID back to pending with a fresh attempt count, for an admin to retry a
+// delivery that's been dead-lettered (or just to force a redelivery).
+func (r *Repository) Redeliver(ctx context.Context, deliveryID uint64) error {
+	const q = `
+UPDATE webhook_deliveries
+SET status = ?, attempts = 0, last_error = '', next_attempt_at = ?
+WHERE id = ?`
+
+	res, err := r.db.ExecContext(ctx, q, StatusPending, time.Now(), deliveryID)
+	if err != nil {
+		return fmt.Errorf("redelivering webhook delivery %d: %w", deliveryID, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking webhook delivery %d update: %w", deliveryID, err)
+	}
+
+	if n == 0 {
+		return ErrDeliveryNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// signatureHeader carries the HMAC-SHA256 signature of the delivered body, GitHub-webhook style.
+const signatureHeader = "X-Geevly-Signature"
+
+// pendingDelivery is a delivery due for an attempt, joined with the subscription it's bound for
+// and the retry policy that was in effect when it was enqueued. subscriptionExists is false when
+// the subscription has since been hard-deleted, in which case url/secret/subscriberActive carry
+// no meaningful value.
+type pendingDelivery struct {
+	Delivery
+	maxAttempts        int
+	initialBackoff     time.Duration
+	url                string
+	secret             string
+	subscriberActive   bool
+	subscriptionExists bool
+}
+
+// runDispatcher polls for due deliveries until ctx is cancelled.
+func (r *Repository) runDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.dispatchDue(ctx)
+		}
+	}
+}
+
+// dispatchDue fans deliveries out by subscription, running one goroutine per subscription that
+// has due deliveries, so a slow or hanging subscriber endpoint only stalls its own subscription's
+// deliveries rather than every subscription's. Deliveries within a single subscription are still
+// attempted in order, on that subscription's goroutine.
+func (r *Repository) dispatchDue(ctx context.Context) {
+	due, err := r.dueDeliveries(ctx, time.Now())
+	if err != nil {
+		slog.Error("loading due webhook deliveries", "error", err)
+		return
+	}
+
+	bySubscription := map[uint64][]pendingDelivery{}
+	for _, d := range due {
+		bySubscription[d.SubscriptionID] = append(bySubscription[d.SubscriptionID], d)
+	}
+
+	var wg sync.WaitGroup
+
+	for _, deliveries := range bySubscription {
+		wg.Add(1)
+
+		go func(deliveries []pendingDelivery) {
+			defer wg.Done()
+
+			for _, d := range deliveries {
+				r.attempt(ctx, d)
+			}
+		}(deliveries)
+	}
+
+	wg.Wait()
+}
+
+func (r *Repository) dueDeliveries(ctx context.Context, now time.Time) ([]pendingDelivery, error) {
+	const q = `
+SELECT d.id, d.subscription_id, d.aggregate_id, d.event_type, d.version, d.timestamp, d.body,
+       d.status, d.attempts, d.max_attempts, d.initial_backoff_seconds, d.last_error, d.next_attempt_at,
+       s.url, s.secret, s.active
+FROM webhook_deliveries d
+LEFT JOIN webhook_subscriptions s ON s.id = d.subscription_id
+WHERE d.status = ? AND d.next_attempt_at <= ?
+ORDER BY d.id ASC`
+
+	rows, err := r.db.QueryContext(ctx, q, StatusPending, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []pendingDelivery
+
+	for rows.Next() {
+		var d pendingDelivery
+		var backoffSeconds int64
+		var url, secret sql.NullString
+		var active sql.NullBool
+
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.AggregateID, &d.EventType, &d.Version, &d.Timestamp, &d.Body,
+			&d.Status, &d.Attempts, &d.maxAttempts, &backoffSeconds, &d.LastError, &d.NextAttemptAt,
+			&url, &secret, &active); err != nil {
+			return nil, err
+		}
+
+		d.initialBackoff = time.Duration(backoffSeconds) * time.Second
+		d.url, d.secret, d.subscriberActive = url.String, secret.String, active.Bool
+		d.subscriptionExists = url.Valid
+
+		due = append(due, d)
+	}
+
+	return due, rows.Err()
+}
+
+// attempt sends d's body to its subscription's URL and records the outcome: delivered on
+// success, or rescheduled with exponential backoff up to the subscription's max attempts, after
+// which the delivery is marked dead-lettered. A subscription that's been deactivated since the
+// delivery was enqueued, or hard-deleted entirely, is dead-lettered immediately rather than
+// retried.
+func (r *Repository) attempt(ctx context.Context, d pendingDelivery) {
+	if !d.subscriptionExists {
+		r.markDeadLetter(ctx, d.ID, "subscription has been deleted")
+		return
+	}
+
+	if !d.subscriberActive {
+		r.markDeadLetter(ctx, d.ID, "subscription is inactive")
+		return
+	}
+
+	err := r.send(ctx, d)
+	if err == nil {
+		r.markDelivered(ctx, d.ID)
+		return
+	}
+
+	attempts := d.Attempts + 1
+	if attempts >= d.maxAttempts {
+		r.markDeadLetter(ctx, d.ID, err.Error())
+		return
+	}
+
+	backoff := d.initialBackoff * time.Duration(1<<uint(attempts-1))
+	r.reschedule(ctx, d.ID, attempts, err.Error(), time.Now().Add(backoff))
+}
+
+func (r *Repository) send(ctx context.Context, d pendingDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(d.Body))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(signatureHeader, "sha256="+sign(d.secret, d.Body))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("subscriber returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// sign computes the hex-encoded HMAC-SHA256 of body using secret.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (r *Repository) markDelivered(ctx context.Context, id uint64) {
+	const q = `UPDATE webhook_deliveries SET status = ?, last_error = '' WHERE id = ?`
+
+	if _, err := r.db.ExecContext(ctx, q, StatusDelivered, id); err != nil {
+		slog.Error("marking webhook delivery delivered", "delivery_id", id, "error", err)
+	}
+}
+
+func (r *Repository) markDeadLetter(ctx context.Context, id uint64, lastError string) {
+	const q = `UPDATE webhook_deliveries SET status = ?, last_error = ? WHERE id = ?`
+
+	if _, err := r.db.ExecContext(ctx, q, StatusDeadLetter, lastError, id); err != nil {
+		slog.Error("marking webhook delivery dead-lettered", "delivery_id", id, "error", err)
+	}
+}
+
+func (r *Repository) reschedule(ctx context.Context, id uint64, attempts int, lastError string, nextAttemptAt time.Time) {
+	const q = `UPDATE webhook_deliveries SET attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?`
+
+	if _, err := r.db.ExecContext(ctx, q, attempts, lastError, nextAttemptAt, id); err != nil {
+		slog.Error("rescheduling webhook delivery", "delivery_id", id, "error", err)
+	}
+}
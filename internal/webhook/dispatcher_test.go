@@ -0,0 +1,278 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// newWebhookTestDB opens an in-memory sqlite database with the subscription and delivery tables
+// created.
+func newWebhookTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("opening test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(subscriptionSchema); err != nil {
+		t.Fatalf("creating subscription schema: %v", err)
+	}
+
+	if _, err := db.Exec(deliverySchema); err != nil {
+		t.Fatalf("creating delivery schema: %v", err)
+	}
+
+	return db
+}
+
+// newTestRepository builds a Repository around db without starting its background dispatcher, so
+// tests can drive dueDeliveries/attempt deterministically instead of racing a polling goroutine.
+func newTestRepository(db *sql.DB) *Repository {
+	return &Repository{db: db, client: &http.Client{Timeout: deliveryTimeout}, pollInterval: time.Second}
+}
+
+// TestSend_SignsBodyWithSubscriptionSecret verifies that send POSTs the delivery body unchanged,
+// with an X-Geevly-Signature header carrying the hex-encoded HMAC-SHA256 of that body under the
+// subscription's secret - the GitHub-webhook-style signature a subscriber is expected to verify.
+func TestSend_SignsBodyWithSubscriptionSecret(t *testing.T) {
+	var gotSig, gotContentType string
+	var gotBody []byte
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request body: %v", err)
+		}
+
+		gotBody = body
+		gotSig = r.Header.Get(signatureHeader)
+		gotContentType = r.Header.Get("Content-Type")
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	repo := newTestRepository(newWebhookTestDB(t))
+
+	d := pendingDelivery{
+		Delivery: Delivery{Body: []byte(`{"hello":"world"}`)},
+		url:      srv.URL,
+		secret:   "shh",
+	}
+
+	if err := repo.send(context.Background(), d); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if want := "sha256=" + sign("shh", d.Body); gotSig != want {
+		t.Fatalf("signature header = %q, want %q", gotSig, want)
+	}
+
+	if gotContentType != "application/json" {
+		t.Fatalf("content-type = %q, want application/json", gotContentType)
+	}
+
+	if string(gotBody) != string(d.Body) {
+		t.Fatalf("delivered body = %q, want %q", gotBody, d.Body)
+	}
+}
+
+// TestSend_ErrorsOnNon2xxStatus verifies that send treats any non-2xx response as a delivery
+// failure, which is what drives attempt's retry/dead-letter bookkeeping.
+func TestSend_ErrorsOnNon2xxStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	repo := newTestRepository(newWebhookTestDB(t))
+	d := pendingDelivery{Delivery: Delivery{Body: []byte(`{}`)}, url: srv.URL, secret: "shh"}
+
+	if err := repo.send(context.Background(), d); err == nil {
+		t.Fatal("send against a 500 response: want error, got nil")
+	}
+}
+
+// TestAttempt_RetriesThenDeadLetters verifies the full backoff/dead-letter lifecycle: a failing
+// delivery is rescheduled (left pending, with attempts incremented) until the subscription's max
+// attempts is reached, at which point it's moved to the dead-letter state.
+func TestAttempt_RetriesThenDeadLetters(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	repo := newTestRepository(newWebhookTestDB(t))
+
+	subID, err := repo.CreateSubscription(ctx, Subscription{
+		URL: srv.URL, Secret: "shh", Active: true,
+		Retry: RetryPolicy{MaxAttempts: 2, InitialBackoff: 0},
+	})
+	if err != nil {
+		t.Fatalf("creating subscription: %v", err)
+	}
+
+	if err := repo.Dispatch(ctx, "evt-1", "SetLookupCode", "42", 1, time.Now(), []byte(`{}`)); err != nil {
+		t.Fatalf("dispatching event: %v", err)
+	}
+
+	due, err := repo.dueDeliveries(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("loading due deliveries: %v", err)
+	}
+
+	if len(due) != 1 {
+		t.Fatalf("got %d due deliveries, want 1", len(due))
+	}
+
+	repo.attempt(ctx, due[0])
+
+	deliveries, err := repo.ListDeliveries(ctx, subID, Pagination{Limit: 10})
+	if err != nil {
+		t.Fatalf("listing deliveries: %v", err)
+	}
+
+	if len(deliveries) != 1 || deliveries[0].Status != StatusPending || deliveries[0].Attempts != 1 {
+		t.Fatalf("after first failed attempt: %+v, want status=%q attempts=1", deliveries[0], StatusPending)
+	}
+
+	due, err = repo.dueDeliveries(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("loading due deliveries after reschedule: %v", err)
+	}
+
+	if len(due) != 1 {
+		t.Fatalf("got %d due deliveries after reschedule, want 1", len(due))
+	}
+
+	repo.attempt(ctx, due[0])
+
+	deliveries, err = repo.ListDeliveries(ctx, subID, Pagination{Limit: 10})
+	if err != nil {
+		t.Fatalf("listing deliveries: %v", err)
+	}
+
+	if deliveries[0].Status != StatusDeadLetter {
+		t.Fatalf("after exhausting retries: status = %q, want %q", deliveries[0].Status, StatusDeadLetter)
+	}
+}
+
+// TestAttempt_DeadLettersDeletedSubscription verifies that a delivery whose subscription has been
+// hard-deleted is dead-lettered rather than left stuck in pending forever (dueDeliveries's LEFT
+// JOIN is what makes these rows visible at all once the subscription row is gone).
+func TestAttempt_DeadLettersDeletedSubscription(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(newWebhookTestDB(t))
+
+	subID, err := repo.CreateSubscription(ctx, Subscription{
+		URL: "http://example.invalid", Secret: "shh", Active: true,
+		Retry: RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("creating subscription: %v", err)
+	}
+
+	if err := repo.Dispatch(ctx, "evt-1", "SetLookupCode", "42", 1, time.Now(), []byte(`{}`)); err != nil {
+		t.Fatalf("dispatching event: %v", err)
+	}
+
+	if err := repo.DeleteSubscription(ctx, subID); err != nil {
+		t.Fatalf("deleting subscription: %v", err)
+	}
+
+	due, err := repo.dueDeliveries(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("loading due deliveries: %v", err)
+	}
+
+	if len(due) != 1 {
+		t.Fatalf("got %d due deliveries, want 1", len(due))
+	}
+
+	if due[0].subscriptionExists {
+		t.Fatal("subscriptionExists = true, want false after DeleteSubscription")
+	}
+
+	repo.attempt(ctx, due[0])
+
+	deliveries, err := repo.ListDeliveries(ctx, 0, Pagination{Limit: 10})
+	if err != nil {
+		t.Fatalf("listing deliveries: %v", err)
+	}
+
+	if deliveries[0].Status != StatusDeadLetter {
+		t.Fatalf("status = %q, want %q", deliveries[0].Status, StatusDeadLetter)
+	}
+
+	if !strings.Contains(deliveries[0].LastError, "deleted") {
+		t.Fatalf("last_error = %q, want it to mention the subscription was deleted", deliveries[0].LastError)
+	}
+}
+
+// TestAttempt_DeadLettersInactiveSubscription verifies that a delivery whose subscription has
+// since been deactivated (soft-disabled, not deleted) is also dead-lettered immediately.
+func TestAttempt_DeadLettersInactiveSubscription(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(newWebhookTestDB(t))
+
+	sub := Subscription{
+		URL: "http://example.invalid", Secret: "shh", Active: true,
+		Retry: RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Minute},
+	}
+
+	subID, err := repo.CreateSubscription(ctx, sub)
+	if err != nil {
+		t.Fatalf("creating subscription: %v", err)
+	}
+
+	if err := repo.Dispatch(ctx, "evt-1", "SetLookupCode", "42", 1, time.Now(), []byte(`{}`)); err != nil {
+		t.Fatalf("dispatching event: %v", err)
+	}
+
+	sub.ID = subID
+	sub.Active = false
+
+	if err := repo.UpdateSubscription(ctx, sub); err != nil {
+		t.Fatalf("deactivating subscription: %v", err)
+	}
+
+	due, err := repo.dueDeliveries(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("loading due deliveries: %v", err)
+	}
+
+	if len(due) != 1 {
+		t.Fatalf("got %d due deliveries, want 1", len(due))
+	}
+
+	if !due[0].subscriptionExists || due[0].subscriberActive {
+		t.Fatalf("subscriptionExists = %v, subscriberActive = %v, want true, false", due[0].subscriptionExists, due[0].subscriberActive)
+	}
+
+	repo.attempt(ctx, due[0])
+
+	deliveries, err := repo.ListDeliveries(ctx, subID, Pagination{Limit: 10})
+	if err != nil {
+		t.Fatalf("listing deliveries: %v", err)
+	}
+
+	if deliveries[0].Status != StatusDeadLetter {
+		t.Fatalf("status = %q, want %q", deliveries[0].Status, StatusDeadLetter)
+	}
+
+	if !strings.Contains(deliveries[0].LastError, "inactive") {
+		t.Fatalf("last_error = %q, want it to mention the subscription is inactive", deliveries[0].LastError)
+	}
+}
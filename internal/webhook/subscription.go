@@ -0,0 +1,229 @@
+// Package webhook manages outbound webhook subscriptions and their deliveries: external systems
+// (school information systems, notification services, ...) register a URL and a secret, and
+// Repository.Dispatch fans matching events out to a signed delivery per subscription. Delivery
+// itself is handled asynchronously by a background dispatcher with exponential backoff and a
+// dead-letter state, independent of whatever produced the event.
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrSubscriptionNotFound is returned when a subscription lookup finds no matching row.
+var ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+// RetryPolicy bounds how many times a delivery is retried, and how quickly retries back off,
+// before it's moved to the dead-letter state.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+}
+
+// DefaultRetryPolicy is applied to a subscription that doesn't specify its own.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Minute}
+
+// Subscription is an external system's registration to receive student-lifecycle events as
+// signed webhook deliveries.
+type Subscription struct {
+	ID         uint64
+	URL        string
+	Secret     string
+	EventTypes []string // event types this subscription receives; empty means every event type
+	Active     bool
+	Retry      RetryPolicy
+}
+
+// Matches reports whether an event of eventType should be delivered to s.
+func (s Subscription) Matches(eventType string) bool {
+	if !s.Active {
+		return false
+	}
+
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+const subscriptionSchema = `
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+	id                      INTEGER PRIMARY KEY AUTOINCREMENT,
+	url                     TEXT NOT NULL,
+	secret                  TEXT NOT NULL,
+	event_types             TEXT NOT NULL DEFAULT '',
+	active                  BOOLEAN NOT NULL DEFAULT 1,
+	max_attempts            INTEGER NOT NULL,
+	initial_backoff_seconds INTEGER NOT NULL,
+	created_at              DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
+
+// eventTypesSep joins/splits Subscription.EventTypes for storage in the single event_types
+// column; student event types are plain identifiers, so a comma can't appear in one.
+const eventTypesSep = ","
+
+func encodeEventTypes(types []string) string {
+	return strings.Join(types, eventTypesSep)
+}
+
+func decodeEventTypes(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, eventTypesSep)
+}
+
+// CreateSubscription inserts sub and returns its assigned ID. A zero-value sub.Retry is filled in
+// with DefaultRetryPolicy.
+func (r *Repository) CreateSubscription(ctx context.Context, sub Subscription) (uint64, error) {
+	if sub.Retry == (RetryPolicy{}) {
+		sub.Retry = DefaultRetryPolicy
+	}
+
+	const q = `
+INSERT INTO webhook_subscriptions (url, secret, event_types, active, max_attempts, initial_backoff_seconds)
+VALUES (?, ?, ?, ?, ?, ?)`
+
+	res, err := r.db.ExecContext(ctx, q, sub.URL, sub.Secret, encodeEventTypes(sub.EventTypes), sub.Active,
+		sub.Retry.MaxAttempts, int64(sub.Retry.InitialBackoff/time.Second))
+	if err != nil {
+		return 0, fmt.Errorf("creating webhook subscription: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("reading new webhook subscription ID: %w", err)
+	}
+
+	return uint64(id), nil
+}
+
+// GetSubscription returns the subscription identified by id, or ErrSubscriptionNotFound if it
+// doesn't exist.
+func (r *Repository) GetSubscription(ctx context.Context, id uint64) (Subscription, error) {
+	const q = `
+SELECT id, url, secret, event_types, active, max_attempts, initial_backoff_seconds
+FROM webhook_subscriptions WHERE id = ?`
+
+	return scanSubscription(r.db.QueryRowContext(ctx, q, id))
+}
+
+// ListSubscriptions returns every webhook subscription, regardless of active state.
+func (r *Repository) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	const q = `SELECT id, url, secret, event_types, active, max_attempts, initial_backoff_seconds FROM webhook_subscriptions ORDER BY id ASC`
+
+	rows, err := r.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []Subscription
+
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+// UpdateSubscription replaces the stored subscription with sub's fields, matched by sub.ID.
+func (r *Repository) UpdateSubscription(ctx context.Context, sub Subscription) error {
+	const q = `
+UPDATE webhook_subscriptions
+SET url = ?, secret = ?, event_types = ?, active = ?, max_attempts = ?, initial_backoff_seconds = ?
+WHERE id = ?`
+
+	res, err := r.db.ExecContext(ctx, q, sub.URL, sub.Secret, encodeEventTypes(sub.EventTypes), sub.Active,
+		sub.Retry.MaxAttempts, int64(sub.Retry.InitialBackoff/time.Second), sub.ID)
+	if err != nil {
+		return fmt.Errorf("updating webhook subscription %d: %w", sub.ID, err)
+	}
+
+	return checkRowAffected(res, sub.ID)
+}
+
+// DeleteSubscription removes the subscription identified by id. Deliveries already enqueued for
+// it are left as-is; the dispatcher dead-letters them the next time it's due, once it notices the
+// subscription is gone.
+func (r *Repository) DeleteSubscription(ctx context.Context, id uint64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("deleting webhook subscription %d: %w", id, err)
+	}
+
+	return checkRowAffected(res, id)
+}
+
+func checkRowAffected(res sql.Result, id uint64) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking webhook subscription %d update: %w", id, err)
+	}
+
+	if n == 0 {
+		return ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows, letting scanSubscription serve both
+// GetSubscription and ListSubscriptions.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSubscription(row rowScanner) (Subscription, error) {
+	var sub Subscription
+	var eventTypes string
+	var backoffSeconds int64
+
+	switch err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, &eventTypes, &sub.Active, &sub.Retry.MaxAttempts, &backoffSeconds); {
+	case errors.Is(err, sql.ErrNoRows):
+		return Subscription{}, ErrSubscriptionNotFound
+	case err != nil:
+		return Subscription{}, fmt.Errorf("scanning webhook subscription: %w", err)
+	}
+
+	sub.EventTypes = decodeEventTypes(eventTypes)
+	sub.Retry.InitialBackoff = time.Duration(backoffSeconds) * time.Second
+
+	return sub, nil
+}
+
+// matchingSubscriptions returns every active subscription whose event-type filter matches
+// eventType.
+func (r *Repository) matchingSubscriptions(ctx context.Context, eventType string) ([]Subscription, error) {
+	subs, err := r.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []Subscription
+
+	for _, sub := range subs {
+		if sub.Matches(eventType) {
+			matched = append(matched, sub)
+		}
+	}
+
+	return matched, nil
+}